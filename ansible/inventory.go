@@ -0,0 +1,112 @@
+// Package ansible knows how to parse Ansible inventory files and to use
+// them to pick a host for a supervisor pod to manage, as well as to keep
+// the supervisor ReplicationController in sync with the hosts found in the
+// inventory.
+package ansible
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// HostEntry represents a single host parsed from an Ansible inventory file
+// along with the connection details needed to supervise it.
+type HostEntry struct {
+	Host                 string
+	User                 string
+	Port                 string
+	Password             string
+	PrivateKey           string
+	PrivateKeyPassphrase string
+	KnownHostsFile       string
+	UseWinRM             bool
+}
+
+// Inventory is the set of groups and hosts parsed from an Ansible inventory
+// file.
+type Inventory struct {
+	Groups map[string][]*HostEntry
+}
+
+// LoadInventory parses the Ansible inventory file at the given path.
+func LoadInventory(file string) (*Inventory, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open inventory file %s: %v", file, err)
+	}
+	defer f.Close()
+
+	inventory := &Inventory{
+		Groups: map[string][]*HostEntry{},
+	}
+	group := "all"
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			group = strings.Trim(line, "[]")
+			continue
+		}
+		entry := parseHostLine(line)
+		inventory.Groups[group] = append(inventory.Groups[group], entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return inventory, nil
+}
+
+// parseHostLine parses a single Ansible inventory host line of the form:
+//
+//	hostname ansible_ssh_host=1.2.3.4 ansible_ssh_user=foo ansible_ssh_private_key_file=/path
+func parseHostLine(line string) *HostEntry {
+	fields := strings.Fields(line)
+	entry := &HostEntry{
+		Host: fields[0],
+	}
+	for _, field := range fields[1:] {
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		key := kv[0]
+		value := kv[1]
+		switch key {
+		case "ansible_ssh_host", "ansible_host":
+			entry.Host = value
+		case "ansible_ssh_user", "ansible_user":
+			entry.User = value
+		case "ansible_ssh_port", "ansible_port":
+			entry.Port = value
+		case "ansible_ssh_private_key_file", "ansible_private_key_file":
+			entry.PrivateKey = value
+		case "ansible_ssh_private_key_passphrase", "private_key_passphrase":
+			entry.PrivateKeyPassphrase = value
+		case "known_hosts_file":
+			entry.KnownHostsFile = value
+		case "ansible_ssh_pass", "ansible_password":
+			entry.Password = value
+		case "winrm", "ansible_connection":
+			entry.UseWinRM = value == "winrm" || value == "true"
+		}
+	}
+	return entry
+}
+
+// HostsInGroup returns all the HostEntry values for the named group, or for
+// every group if the name is empty.
+func (i *Inventory) HostsInGroup(group string) []*HostEntry {
+	if len(group) == 0 {
+		var all []*HostEntry
+		for _, hosts := range i.Groups {
+			all = append(all, hosts...)
+		}
+		return all
+	}
+	return i.Groups[group]
+}
@@ -0,0 +1,91 @@
+package ansible
+
+import (
+	"fmt"
+
+	"github.com/fabric8io/gosupervise/log"
+
+	"k8s.io/kubernetes/pkg/api"
+	client "k8s.io/kubernetes/pkg/client/unversioned"
+)
+
+// ChooseHostAndPrivateKey parses the Ansible inventory and returns the first
+// HostEntry in the given group for which isAvailable returns true, trying
+// each host in inventory order and returning an error once every host has
+// been tried. isAvailable is typically backed by a hostlock.Locker so that
+// replicas of the same ReplicationController don't pick the same host; pass
+// nil to accept the first host in the group unconditionally.
+func ChooseHostAndPrivateKey(inventory string, hosts string, isAvailable func(host string) (bool, error)) (*HostEntry, error) {
+	inv, err := LoadInventory(inventory)
+	if err != nil {
+		return nil, err
+	}
+	entries := inv.HostsInGroup(hosts)
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("No hosts found in inventory group %s", hosts)
+	}
+
+	for _, entry := range entries {
+		if isAvailable != nil {
+			ok, err := isAvailable(entry.Host)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+		}
+		log.Info("Chose host %s from inventory group %s", entry.Host, hosts)
+		return entry, nil
+	}
+	return nil, fmt.Errorf("All hosts in inventory group %s are already claimed", hosts)
+}
+
+// UpdateAnsibleRC creates or updates the ReplicationController used to run
+// one supervisor pod per host in the given Ansible inventory group.
+func UpdateAnsibleRC(inventory string, hosts string, kubeclient *client.Client, ns string, rcName string) (*api.ReplicationController, error) {
+	inv, err := LoadInventory(inventory)
+	if err != nil {
+		return nil, err
+	}
+	entries := inv.HostsInGroup(hosts)
+	replicas := len(entries)
+	if replicas == 0 {
+		return nil, fmt.Errorf("No hosts found in inventory group %s", hosts)
+	}
+
+	rcs := kubeclient.ReplicationControllers(ns)
+	existing, err := rcs.Get(rcName)
+	if err == nil && existing != nil {
+		existing.Spec.Replicas = replicas
+		log.Info("Updating ReplicationController %s to %d replicas for inventory group %s", rcName, replicas, hosts)
+		return rcs.Update(existing)
+	}
+
+	rc := &api.ReplicationController{
+		ObjectMeta: api.ObjectMeta{
+			Name:      rcName,
+			Namespace: ns,
+			Labels:    map[string]string{"provider": "gosupervise", "rc": rcName},
+		},
+		Spec: api.ReplicationControllerSpec{
+			Replicas: replicas,
+			Selector: map[string]string{"provider": "gosupervise", "rc": rcName},
+			Template: &api.PodTemplateSpec{
+				ObjectMeta: api.ObjectMeta{
+					Labels: map[string]string{"provider": "gosupervise", "rc": rcName},
+				},
+				Spec: api.PodSpec{
+					Containers: []api.Container{
+						{
+							Name:  "supervisor",
+							Image: "fabric8/gosupervise:latest",
+						},
+					},
+				},
+			},
+		},
+	}
+	log.Info("Creating ReplicationController %s with %d replicas for inventory group %s", rcName, replicas, hosts)
+	return rcs.Create(rc)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fabric8io/gosupervise/ansible"
+	"github.com/fabric8io/gosupervise/ssh"
+
+	"github.com/codegangsta/cli"
+	client "k8s.io/kubernetes/pkg/client/unversioned"
+)
+
+// buildAuthConfig combines the --key-secret, --known-hosts and
+// --strict-host-key-checking flags with any per-host settings from the
+// Ansible inventory into the ssh.AuthConfig used to connect to hostEntry.
+// kubeclient may be nil if --key-secret is not used.
+func buildAuthConfig(c *cli.Context, kubeclient *client.Client, privateKey string, hostEntry *ansible.HostEntry) (ssh.AuthConfig, error) {
+	auth := ssh.AuthConfig{
+		PrivateKeyFile:        privateKey,
+		UseAgent:              c.Bool("ssh-agent"),
+		StrictHostKeyChecking: osExpand(c, "strict-host-key-checking"),
+	}
+	if hostEntry != nil {
+		auth.Passphrase = hostEntry.PrivateKeyPassphrase
+		auth.KnownHostsFile = hostEntry.KnownHostsFile
+	}
+	if knownHosts := osExpand(c, "known-hosts"); len(knownHosts) > 0 {
+		auth.KnownHostsFile = knownHosts
+	}
+
+	keySecret := osExpand(c, "key-secret")
+	if len(keySecret) > 0 {
+		passphrase, err := readSecretValue(kubeclient, keySecret)
+		if err != nil {
+			return auth, err
+		}
+		auth.Passphrase = passphrase
+	}
+	return auth, nil
+}
+
+// readSecretValue reads a single key out of a Kubernetes Secret referenced
+// as "namespace/name/key".
+func readSecretValue(kubeclient *client.Client, ref string) (string, error) {
+	parts := strings.SplitN(ref, "/", 3)
+	if len(parts) != 3 {
+		return "", fmt.Errorf("Invalid --key-secret value %s, expected namespace/name/key", ref)
+	}
+	ns, name, key := parts[0], parts[1], parts[2]
+
+	secret, err := kubeclient.Secrets(ns).Get(name)
+	if err != nil {
+		return "", fmt.Errorf("Failed to load Secret %s/%s: %v", ns, name, err)
+	}
+	value, found := secret.Data[key]
+	if !found {
+		return "", fmt.Errorf("Secret %s/%s has no key %s", ns, name, key)
+	}
+	return string(value), nil
+}
+
+// authFlags are the common SSH authentication and host key verification
+// flags shared by commands that connect to a host over SSH.
+var authFlags = []cli.Flag{
+	cli.BoolFlag{
+		Name:  "ssh-agent",
+		Usage: "Authenticate using the SSH agent at $SSH_AUTH_SOCK instead of a private key file",
+	},
+	cli.StringFlag{
+		Name:  "key-secret",
+		Usage: "namespace/name/key of a Kubernetes Secret holding the private key passphrase",
+	},
+	cli.StringFlag{
+		Name:  "known-hosts",
+		Usage: "The known_hosts file used to verify remote host keys",
+	},
+	cli.StringFlag{
+		Name:  "strict-host-key-checking",
+		Value: "no",
+		Usage: "One of yes, accept-new or no",
+	},
+}
@@ -10,11 +10,12 @@ import (
 	"github.com/codegangsta/cli"
 
 	"github.com/fabric8io/gosupervise/ansible"
+	"github.com/fabric8io/gosupervise/hostlock"
 	"github.com/fabric8io/gosupervise/log"
 	"github.com/fabric8io/gosupervise/ssh"
 	"github.com/fabric8io/gosupervise/winrm"
 
-	cmdutil "k8s.io/kubernetes/pkg/kubectl/cmd/util"
+	client "k8s.io/kubernetes/pkg/client/unversioned"
 )
 
 // version is the version of the app.
@@ -69,7 +70,7 @@ running inside Docker inside Kubernetes.
 			Description: `This commmand will begin running the supervisor command on one host from the Ansible inventory.`,
 			ArgsUsage: "[hosts] [command]",
 			Action: runAnsiblePod,
-			Flags: []cli.Flag{
+			Flags: append([]cli.Flag{
 				cli.StringFlag{
 					Name:   "inventory",
 					Value:  "inventory",
@@ -95,7 +96,21 @@ running inside Docker inside Kubernetes.
 					Value:  "$GOSUPERVISE_BASH",
 					Usage:  "If specified a script is generated for running a bash like shell on the remote machine",
 				},
-			},
+				cli.BoolFlag{
+					Name:   "tty, interactive",
+					Usage:  "Opens an interactive PTY session on the remote host instead of running the command and exiting",
+				},
+				cli.DurationFlag{
+					Name:  "lease-duration",
+					Value: hostlock.DefaultLeaseDuration,
+					Usage: "How long this pod's claim on its host is valid for before it must be renewed",
+				},
+				cli.DurationFlag{
+					Name:  "renew-deadline",
+					Value: hostlock.DefaultRenewDeadline,
+					Usage: "How often this pod renews its claim on its host",
+				},
+			}, authFlags...),
 		},
 		{
 			Name:    "rc",
@@ -122,7 +137,7 @@ running inside Docker inside Kubernetes.
 			Description: `This commmand will begin running the supervisor on an avaiable host.`,
 			ArgsUsage: "[string]",
 			Action: run,
-			Flags: []cli.Flag{
+			Flags: append([]cli.Flag{
 				cli.StringFlag{
 					Name:   "user",
 					Value:  "$GOSUPERVISE_USER",
@@ -151,7 +166,34 @@ running inside Docker inside Kubernetes.
 					Name:   "winrm",
 					Usage:  "Enables the use of WinRM instead of SSH",
 				},
-			},
+				cli.BoolFlag{
+					Name:   "tty, interactive",
+					Usage:  "Opens an interactive PTY session on the remote host instead of running the command and exiting",
+				},
+			}, authFlags...),
+		},
+		{
+			Name:    "port-forward",
+			Usage:   "Forwards TCP ports between the supervisor pod and the legacy host.",
+			Description: `This commmand chooses a host from an Ansible inventory and forwards TCP ports between this pod and it, like kubectl port-forward but terminating on the legacy VM.`,
+			ArgsUsage: "[hosts] [localPort:remotePort]...",
+			Action: portForward,
+			Flags: append([]cli.Flag{
+				cli.StringFlag{
+					Name:   "inventory",
+					Value:  "inventory",
+					Usage:  "The location of your Ansible inventory file",
+				},
+				cli.BoolFlag{
+					Name:   "winrm",
+					EnvVar: "GOSUPERVISE_WINRM",
+					Usage:  "Not supported: WSMan has no native port-forwarding mechanism, so this always fails. Forward ports to Windows hosts over SSH instead.",
+				},
+				cli.BoolFlag{
+					Name:   "R, reverse",
+					Usage:  "Forwards remote ports back to this pod instead of local ports to the remote host",
+				},
+			}, authFlags...),
 		},
 	}
 
@@ -200,17 +242,9 @@ func applyAnsibleRC(c *cli.Context) {
 	}
 	hosts := args[0]
 
-	f := cmdutil.NewFactory(nil)
-	if f == nil {
-		log.Die("Failed to create Kuberentes client factory!")
-	}
-	kubeclient, _ := f.Client()
-	if kubeclient == nil {
-		log.Die("Failed to create Kuberentes client!")
-	}
-	ns, _, _ := f.DefaultNamespace()
-	if len(ns) == 0 {
-		ns = "default"
+	kubeclient, ns, err := createKubernetesClient()
+	if err != nil {
+		fail(err)
 	}
 
 	rcName, err := osExpandAndVerify(c, "rc")
@@ -238,17 +272,9 @@ func runAnsiblePod(c *cli.Context) {
 
 	log.Info("running command on a host from %s and command `%s`", hosts, command)
 
-	f := cmdutil.NewFactory(nil)
-	if f == nil {
-		log.Die("Failed to create Kuberentes client factory!")
-	}
-	kubeclient, _ := f.Client()
-	if kubeclient == nil {
-		log.Die("Failed to create Kuberentes client!")
-	}
-	ns, _, _ := f.DefaultNamespace()
-	if len(ns) == 0 {
-		ns = "default"
+	kubeclient, ns, err := createKubernetesClient()
+	if err != nil {
+		fail(err)
 	}
 
 	inventory, err := osExpandAndVerify(c, "inventory")
@@ -259,7 +285,9 @@ func runAnsiblePod(c *cli.Context) {
 	if err != nil {
 		fail(err)
 	}
-	hostEntry, err := ansible.ChooseHostAndPrivateKey(inventory, hosts, kubeclient, ns, rcName)
+
+	locker := hostlock.NewKubernetesLocker(kubeclient, ns, rcName, podHolderID(), c.Duration("lease-duration"))
+	hostEntry, err := ansible.ChooseHostAndPrivateKey(inventory, hosts, locker.Acquire)
 	if err != nil {
 		fail(err)
 	}
@@ -273,7 +301,20 @@ func runAnsiblePod(c *cli.Context) {
 		fail(err)
 	}
 
+	stopRenewing := make(chan struct{})
+	leaseLost := hostlock.KeepRenewed(locker, host, c.Duration("renew-deadline"), stopRenewing)
+	defer releaseHostLease(locker, host, stopRenewing)
+	go func() {
+		if err := <-leaseLost; err != nil {
+			log.Die("Lost lease on host %s: %v", host, err)
+		}
+	}()
+	exitOnSigterm(func() {
+		releaseHostLease(locker, host, stopRenewing)
+	})
+
 	useWinRM := c.Bool("winrm") || hostEntry.UseWinRM
+	tty := c.Bool("tty")
 
 	bash := osExpand(c, "bash")
 	if len(bash) > 0 {
@@ -292,10 +333,73 @@ func runAnsiblePod(c *cli.Context) {
 				fail(err)
 			}
 		}
-		err = winrm.RemoteWinRmCommand(user, password, host, port, command)
+		err = winrm.RemoteWinRmCommand(user, password, host, port, command, tty)
 	} else {
-		privatekey := hostEntry.PrivateKey
-		err = ssh.RemoteSshCommand(user, privatekey, host, port, command)
+		auth, authErr := buildAuthConfig(c, kubeclient, hostEntry.PrivateKey, hostEntry)
+		if authErr != nil {
+			fail(authErr)
+		}
+		err = ssh.RemoteSshCommand(user, auth, host, port, command, tty)
+	}
+	if err != nil {
+		log.Err("Failed: %v", err)
+	}
+}
+
+func portForward(c *cli.Context) {
+	args := c.Args()
+	if len(args) < 2 {
+		log.Die("Expected a hosts argument followed by at least one localPort:remotePort mapping!")
+	}
+	hosts := args[0]
+
+	mappings := make([]ssh.PortMapping, 0, len(args)-1)
+	for _, spec := range args[1:] {
+		mapping, err := ssh.ParsePortMapping(spec)
+		if err != nil {
+			fail(err)
+		}
+		mappings = append(mappings, *mapping)
+	}
+
+	inventory, err := osExpandAndVerify(c, "inventory")
+	if err != nil {
+		fail(err)
+	}
+	hostEntry, err := ansible.ChooseHostAndPrivateKey(inventory, hosts, nil)
+	if err != nil {
+		fail(err)
+	}
+	host := hostEntry.Host
+	port := hostEntry.Port
+	if len(port) == 0 {
+		port, err = osExpandAndVerifyGlobal(c, "port")
+		if err != nil {
+			fail(err)
+		}
+	}
+
+	reverse := c.Bool("reverse")
+	useWinRM := c.Bool("winrm") || hostEntry.UseWinRM
+	if useWinRM {
+		winrmMappings := make([]winrm.PortMapping, len(mappings))
+		for i, m := range mappings {
+			winrmMappings[i] = winrm.PortMapping{LocalPort: m.LocalPort, RemotePort: m.RemotePort}
+		}
+		err = winrm.PortForward(hostEntry.User, hostEntry.Password, host, port, winrmMappings, reverse)
+	} else {
+		var kubeclient *client.Client
+		if len(osExpand(c, "key-secret")) > 0 {
+			kubeclient, _, err = createKubernetesClient()
+			if err != nil {
+				fail(err)
+			}
+		}
+		auth, authErr := buildAuthConfig(c, kubeclient, hostEntry.PrivateKey, hostEntry)
+		if authErr != nil {
+			fail(authErr)
+		}
+		err = ssh.PortForward(hostEntry.User, auth, host, port, mappings, reverse)
 	}
 	if err != nil {
 		log.Err("Failed: %v", err)
@@ -307,7 +411,7 @@ func generateBashScript(file string, useWinRM bool) error {
 	if useWinRM {
 		shellCommand = "PowerShell"
 	}
-	text :=  "#!/bin/sh\n" + "echo opening shell on remote machine...\n" + "pod appservers " + shellCommand + "\n";
+	text := "#!/bin/sh\n" + "echo opening shell on remote machine...\n" + "pod appservers --tty " + shellCommand + "\n"
 	return ioutil.WriteFile(file, []byte(text), 0555)
 }
 
@@ -331,18 +435,30 @@ func run(c *cli.Context) {
 		fail(err)
 	}
 	useWinRM := c.Bool("winrm")
+	tty := c.Bool("tty")
 	if useWinRM {
 		password, err := osExpandAndVerify(c, "password")
 		if err != nil {
 			fail(err)
 		}
-		err = winrm.RemoteWinRmCommand(user, password, host, port, command)
+		err = winrm.RemoteWinRmCommand(user, password, host, port, command, tty)
 	} else {
 		privatekey, err := osExpandAndVerify(c, "privatekey")
 		if err != nil {
 			fail(err)
 		}
-		err = ssh.RemoteSshCommand(user, privatekey, host, port, command)
+		var kubeclient *client.Client
+		if len(osExpand(c, "key-secret")) > 0 {
+			kubeclient, _, err = createKubernetesClient()
+			if err != nil {
+				fail(err)
+			}
+		}
+		auth, err := buildAuthConfig(c, kubeclient, privatekey, nil)
+		if err != nil {
+			fail(err)
+		}
+		err = ssh.RemoteSshCommand(user, auth, host, port, command, tty)
 	}
 	if err != nil {
 		log.Err("Failed: %v", err)
@@ -0,0 +1,42 @@
+// Package hostlock coordinates which Ansible inventory host each replica of
+// the supervisor ReplicationController is allowed to manage, so that two
+// pods never grab the same host.
+package hostlock
+
+import "time"
+
+// DefaultLeaseDuration is how long a claimed host lease is valid for before
+// it must be renewed or is considered abandoned.
+const DefaultLeaseDuration = 30 * time.Second
+
+// DefaultRenewDeadline is how often a holder renews its lease. A single
+// failed renewal is treated as losing the lease.
+const DefaultRenewDeadline = 20 * time.Second
+
+// Lease records who currently holds a host and until when.
+type Lease struct {
+	Host      string    `json:"host"`
+	Holder    string    `json:"holder"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// expired reports whether the lease has passed its expiry time.
+func (l Lease) expired(now time.Time) bool {
+	return now.After(l.ExpiresAt)
+}
+
+// Locker is implemented by pluggable host-election backends. Callers use it
+// to claim exclusive ownership of a host for as long as they keep renewing
+// the lease, and to give it up on shutdown.
+type Locker interface {
+	// Acquire attempts to claim host for this holder. It returns false,
+	// nil if the host is already validly held by someone else.
+	Acquire(host string) (bool, error)
+
+	// Renew extends this holder's lease on host. It returns an error if
+	// the lease is no longer held by this holder.
+	Renew(host string) error
+
+	// Release gives up this holder's lease on host, if held.
+	Release(host string) error
+}
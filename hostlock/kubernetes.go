@@ -0,0 +1,170 @@
+package hostlock
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/fabric8io/gosupervise/log"
+
+	"k8s.io/kubernetes/pkg/api"
+	apierrors "k8s.io/kubernetes/pkg/api/errors"
+	client "k8s.io/kubernetes/pkg/client/unversioned"
+)
+
+// leaseAnnotation is the ReplicationController annotation that stores the
+// JSON encoded map of host -> Lease for every host currently claimed by one
+// of its pods.
+const leaseAnnotation = "gosupervise.fabric8.io/host-leases"
+
+// KubernetesLocker is a Locker backed by annotations on a
+// ReplicationController, using the resourceVersion returned on each Get as
+// optimistic concurrency so that racing replicas don't double-claim a host.
+type KubernetesLocker struct {
+	kubeclient    *client.Client
+	ns            string
+	rcName        string
+	holder        string
+	leaseDuration time.Duration
+}
+
+// NewKubernetesLocker creates a Locker that claims hosts via annotations on
+// the named ReplicationController. holder should uniquely identify this
+// process, e.g. the pod name.
+func NewKubernetesLocker(kubeclient *client.Client, ns string, rcName string, holder string, leaseDuration time.Duration) *KubernetesLocker {
+	if leaseDuration <= 0 {
+		leaseDuration = DefaultLeaseDuration
+	}
+	return &KubernetesLocker{
+		kubeclient:    kubeclient,
+		ns:            ns,
+		rcName:        rcName,
+		holder:        holder,
+		leaseDuration: leaseDuration,
+	}
+}
+
+// Acquire implements Locker by claiming host in the RC's lease annotation,
+// retrying on optimistic concurrency conflicts.
+func (l *KubernetesLocker) Acquire(host string) (bool, error) {
+	for {
+		rc, leases, now, err := l.load()
+		if err != nil {
+			return false, err
+		}
+
+		if existing, found := leases[host]; found && !existing.expired(now) && existing.Holder != l.holder {
+			return false, nil
+		}
+
+		leases[host] = l.newLease(host, now)
+		if err := l.save(rc, leases); err != nil {
+			if apierrors.IsConflict(err) {
+				continue
+			}
+			return false, err
+		}
+		log.Debug("Claimed lease on host %s for %s until %s", host, l.holder, leases[host].ExpiresAt)
+		return true, nil
+	}
+}
+
+// Renew implements Locker by extending this holder's existing lease.
+func (l *KubernetesLocker) Renew(host string) error {
+	for {
+		rc, leases, now, err := l.load()
+		if err != nil {
+			return err
+		}
+
+		existing, found := leases[host]
+		if !found || (existing.Holder != l.holder && !existing.expired(now)) {
+			return fmt.Errorf("Lost lease on host %s", host)
+		}
+
+		leases[host] = l.newLease(host, now)
+		if err := l.save(rc, leases); err != nil {
+			if apierrors.IsConflict(err) {
+				continue
+			}
+			return err
+		}
+		return nil
+	}
+}
+
+// Release implements Locker by removing this holder's lease on host, if it
+// still owns it.
+func (l *KubernetesLocker) Release(host string) error {
+	for {
+		rc, leases, _, err := l.load()
+		if err != nil {
+			return err
+		}
+
+		existing, found := leases[host]
+		if !found || existing.Holder != l.holder {
+			return nil
+		}
+		delete(leases, host)
+
+		if err := l.save(rc, leases); err != nil {
+			if apierrors.IsConflict(err) {
+				continue
+			}
+			return err
+		}
+		log.Info("Released lease on host %s", host)
+		return nil
+	}
+}
+
+func (l *KubernetesLocker) newLease(host string, now time.Time) Lease {
+	return Lease{
+		Host:      host,
+		Holder:    l.holder,
+		ExpiresAt: now.Add(l.leaseDuration),
+	}
+}
+
+// load fetches the current ReplicationController and decodes its lease
+// annotation.
+func (l *KubernetesLocker) load() (*api.ReplicationController, map[string]Lease, time.Time, error) {
+	rc, err := l.kubeclient.ReplicationControllers(l.ns).Get(l.rcName)
+	if err != nil {
+		return nil, nil, time.Time{}, fmt.Errorf("Failed to load ReplicationController %s: %v", l.rcName, err)
+	}
+	return rc, decodeLeases(rc.Annotations), time.Now(), nil
+}
+
+// save encodes leases back onto rc's annotation and updates it. A conflict
+// error is returned verbatim so callers can retry against a fresh Get.
+func (l *KubernetesLocker) save(rc *api.ReplicationController, leases map[string]Lease) error {
+	encoded, err := json.Marshal(leases)
+	if err != nil {
+		return fmt.Errorf("Failed to encode host leases: %v", err)
+	}
+	if rc.Annotations == nil {
+		rc.Annotations = map[string]string{}
+	}
+	rc.Annotations[leaseAnnotation] = string(encoded)
+
+	_, err = l.kubeclient.ReplicationControllers(l.ns).Update(rc)
+	return err
+}
+
+func decodeLeases(annotations map[string]string) map[string]Lease {
+	leases := map[string]Lease{}
+	if annotations == nil {
+		return leases
+	}
+	raw, found := annotations[leaseAnnotation]
+	if !found || len(raw) == 0 {
+		return leases
+	}
+	if err := json.Unmarshal([]byte(raw), &leases); err != nil {
+		log.Err("Failed to decode host leases: %v", err)
+		return map[string]Lease{}
+	}
+	return leases
+}
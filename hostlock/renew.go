@@ -0,0 +1,31 @@
+package hostlock
+
+import "time"
+
+// KeepRenewed renews the lease on host against locker every renewDeadline
+// until stopCh is closed. If a renewal fails the lease is considered lost
+// and the returned channel is sent an error; callers should treat this as
+// fatal (e.g. exit so Kubernetes reschedules the pod) since another replica
+// may now believe it owns the host.
+func KeepRenewed(locker Locker, host string, renewDeadline time.Duration, stopCh <-chan struct{}) <-chan error {
+	if renewDeadline <= 0 {
+		renewDeadline = DefaultRenewDeadline
+	}
+	lost := make(chan error, 1)
+	go func() {
+		ticker := time.NewTicker(renewDeadline)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-ticker.C:
+				if err := locker.Renew(host); err != nil {
+					lost <- err
+					return
+				}
+			}
+		}
+	}()
+	return lost
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"io/ioutil"
+	"strings"
+
+	"github.com/fabric8io/gosupervise/log"
+
+	kclient "k8s.io/kubernetes/pkg/client/unversioned"
+	cmdutil "k8s.io/kubernetes/pkg/kubectl/cmd/util"
+)
+
+// serviceAccountNamespaceFile is the namespace of the service account the
+// pod is running as, as mounted by Kubernetes into every pod.
+const serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// createKubernetesClient returns a Kubernetes client and the namespace to
+// operate in. When gosupervise is itself running inside a pod it uses the
+// in-cluster config from the mounted service account token, so the
+// supervisor pods can talk to the API server without a kubeconfig. It falls
+// back to the kubeconfig based factory when not running in a cluster.
+func createKubernetesClient() (*kclient.Client, string, error) {
+	config, err := kclient.InClusterConfig()
+	if err == nil {
+		kubeclient, err := kclient.New(config)
+		if err != nil {
+			return nil, "", err
+		}
+		log.Debug("Using in-cluster Kubernetes config")
+		return kubeclient, inClusterNamespace(), nil
+	}
+
+	f := cmdutil.NewFactory(nil)
+	if f == nil {
+		log.Die("Failed to create Kuberentes client factory!")
+	}
+	kubeclient, err := f.Client()
+	if err != nil {
+		return nil, "", err
+	}
+	ns, _, _ := f.DefaultNamespace()
+	if len(ns) == 0 {
+		ns = "default"
+	}
+	return kubeclient, ns, nil
+}
+
+// inClusterNamespace reads the namespace of the service account that the
+// current pod is running as, falling back to "default" if it can't be
+// determined.
+func inClusterNamespace() string {
+	data, err := ioutil.ReadFile(serviceAccountNamespaceFile)
+	if err != nil {
+		return "default"
+	}
+	ns := strings.TrimSpace(string(data))
+	if len(ns) == 0 {
+		return "default"
+	}
+	return ns
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/fabric8io/gosupervise/hostlock"
+	"github.com/fabric8io/gosupervise/log"
+)
+
+// podHolderID identifies this process to the host lock, so that a renewal
+// or release can tell its own lease apart from another replica's. The pod
+// name is injected into HOSTNAME by Kubernetes; we fall back to the OS
+// hostname outside a cluster.
+func podHolderID() string {
+	if hostname := os.Getenv("HOSTNAME"); len(hostname) > 0 {
+		return hostname
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "unknown"
+	}
+	return hostname
+}
+
+var releaseOnce sync.Once
+
+// releaseHostLease stops the renewal goroutine and releases host exactly
+// once, so it is safe to call both from a defer and from a signal handler.
+func releaseHostLease(locker hostlock.Locker, host string, stopRenewing chan struct{}) {
+	releaseOnce.Do(func() {
+		close(stopRenewing)
+		if err := locker.Release(host); err != nil {
+			log.Err("Failed to release lease on host %s: %v", host, err)
+		}
+	})
+}
+
+// exitOnSigterm runs cleanup and exits as soon as the process receives
+// SIGTERM, so that a Kubernetes-initiated pod shutdown releases the host
+// lease promptly instead of waiting for the grace period to expire.
+func exitOnSigterm(cleanup func()) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		cleanup()
+		os.Exit(0)
+	}()
+}
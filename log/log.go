@@ -0,0 +1,42 @@
+// Package log provides simple leveled logging for the gosupervise command
+// line tool and its sub-packages.
+package log
+
+import (
+	"fmt"
+	"os"
+)
+
+// IsDebugging controls whether Debug() output is printed. It is toggled by
+// the --debug global flag.
+var IsDebugging = false
+
+// ErrorState is set to true whenever Err() is called, so that the app can
+// exit with a non-zero status once the command has finished running.
+var ErrorState = false
+
+// Info prints an informational message to stdout.
+func Info(format string, a ...interface{}) {
+	fmt.Fprintf(os.Stdout, format+"\n", a...)
+}
+
+// Debug prints a debug message to stdout when IsDebugging is enabled.
+func Debug(format string, a ...interface{}) {
+	if IsDebugging {
+		fmt.Fprintf(os.Stdout, "DEBUG: "+format+"\n", a...)
+	}
+}
+
+// Err prints an error message to stderr and flags ErrorState so the process
+// can later exit with a failure code.
+func Err(format string, a ...interface{}) {
+	ErrorState = true
+	fmt.Fprintf(os.Stderr, "ERROR: "+format+"\n", a...)
+}
+
+// Die prints an error message to stderr and immediately exits the process
+// with a non-zero status.
+func Die(format string, a ...interface{}) {
+	fmt.Fprintf(os.Stderr, "ERROR: "+format+"\n", a...)
+	os.Exit(1)
+}
@@ -0,0 +1,117 @@
+package ssh
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// AuthConfig describes how to authenticate and verify the server for an SSH
+// connection to a single host.
+type AuthConfig struct {
+	// PrivateKeyFile is the path to a PEM private key to authenticate
+	// with. Ignored if UseAgent is true.
+	PrivateKeyFile string
+
+	// Passphrase decrypts PrivateKeyFile when it is encrypted. Leave
+	// empty for an unencrypted key.
+	Passphrase string
+
+	// UseAgent authenticates via the agent listening on SSH_AUTH_SOCK
+	// instead of PrivateKeyFile.
+	UseAgent bool
+
+	// KnownHostsFile, if set, verifies the server's host key against
+	// this known_hosts file. Required unless StrictHostKeyChecking is
+	// "no".
+	KnownHostsFile string
+
+	// StrictHostKeyChecking is one of "yes", "accept-new" or "no",
+	// mirroring OpenSSH's option of the same name. "yes" rejects
+	// unknown hosts, "accept-new" trusts them on first use, and "no"
+	// disables host key verification entirely.
+	StrictHostKeyChecking string
+}
+
+// clientConfig builds the golang.org/x/crypto/ssh.ClientConfig for auth,
+// resolving its auth method and host key callback.
+func (auth AuthConfig) clientConfig(user string) (*ssh.ClientConfig, error) {
+	authMethod, err := auth.authMethod()
+	if err != nil {
+		return nil, err
+	}
+	hostKeyCallback, err := auth.hostKeyCallback()
+	if err != nil {
+		return nil, err
+	}
+	return &ssh.ClientConfig{
+		User:            user,
+		Auth:            []ssh.AuthMethod{authMethod},
+		HostKeyCallback: hostKeyCallback,
+	}, nil
+}
+
+// authMethod resolves either agent-based or private-key-based auth
+// depending on how AuthConfig is populated.
+func (auth AuthConfig) authMethod() (ssh.AuthMethod, error) {
+	if auth.UseAgent {
+		sock := os.Getenv("SSH_AUTH_SOCK")
+		if len(sock) == 0 {
+			return nil, fmt.Errorf("SSH agent auth requested but SSH_AUTH_SOCK is not set")
+		}
+		conn, err := net.Dial("unix", sock)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to connect to SSH agent at %s: %v", sock, err)
+		}
+		agentClient := agent.NewClient(conn)
+		return ssh.PublicKeysCallback(agentClient.Signers), nil
+	}
+
+	key, err := ioutil.ReadFile(auth.PrivateKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read private key file %s: %v", auth.PrivateKeyFile, err)
+	}
+
+	var signer ssh.Signer
+	if len(auth.Passphrase) > 0 {
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(key, []byte(auth.Passphrase))
+	} else {
+		signer, err = ssh.ParsePrivateKey(key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse private key file %s: %v", auth.PrivateKeyFile, err)
+	}
+	return ssh.PublicKeys(signer), nil
+}
+
+// hostKeyCallback resolves the HostKeyCallback implied by
+// StrictHostKeyChecking and KnownHostsFile.
+func (auth AuthConfig) hostKeyCallback() (ssh.HostKeyCallback, error) {
+	switch auth.StrictHostKeyChecking {
+	case "", "no":
+		return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			return nil
+		}, nil
+	case "yes":
+		if len(auth.KnownHostsFile) == 0 {
+			return nil, fmt.Errorf("--strict-host-key-checking=yes requires a known_hosts file")
+		}
+		callback, err := knownhosts.New(auth.KnownHostsFile)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to load known_hosts file %s: %v", auth.KnownHostsFile, err)
+		}
+		return callback, nil
+	case "accept-new":
+		if len(auth.KnownHostsFile) == 0 {
+			return nil, fmt.Errorf("--strict-host-key-checking=accept-new requires a known_hosts file")
+		}
+		return acceptNewHostKeyCallback(auth.KnownHostsFile)
+	default:
+		return nil, fmt.Errorf("Invalid value for --strict-host-key-checking: %s", auth.StrictHostKeyChecking)
+	}
+}
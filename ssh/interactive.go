@@ -0,0 +1,96 @@
+package ssh
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/fabric8io/gosupervise/log"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// RunInteractive opens an interactive PTY session over an already dialled
+// SSH client, wiring up the local terminal's stdin/stdout/stderr and
+// forwarding window size changes, so it behaves like `kubectl exec -it`
+// landing the caller in a live shell on the remote host. If command is
+// empty the remote user's login shell is started instead.
+func RunInteractive(client *ssh.Client, host string, command string) error {
+	session, err := client.NewSession()
+	if err != nil {
+		return fmt.Errorf("Failed to create SSH session to %s: %v", host, err)
+	}
+	defer session.Close()
+
+	session.Stdout = os.Stdout
+	session.Stderr = os.Stderr
+	session.Stdin = os.Stdin
+
+	fd := int(os.Stdin.Fd())
+	width, height := 80, 40
+	isTerminal := terminal.IsTerminal(fd)
+	if isTerminal {
+		if w, h, err := terminal.GetSize(fd); err == nil {
+			width, height = w, h
+		}
+
+		oldState, err := terminal.MakeRaw(fd)
+		if err != nil {
+			return fmt.Errorf("Failed to put local terminal into raw mode: %v", err)
+		}
+		defer terminal.Restore(fd, oldState)
+	}
+
+	modes := ssh.TerminalModes{
+		ssh.ECHO:          1,
+		ssh.TTY_OP_ISPEED: 14400,
+		ssh.TTY_OP_OSPEED: 14400,
+	}
+	if err := session.RequestPty("xterm", height, width, modes); err != nil {
+		return fmt.Errorf("Failed to request PTY on %s: %v", host, err)
+	}
+
+	if isTerminal {
+		stopResize := forwardWindowResize(session, fd)
+		defer close(stopResize)
+	}
+
+	log.Info("Opening interactive session on %s", host)
+
+	if len(command) == 0 {
+		if err := session.Shell(); err != nil {
+			return fmt.Errorf("Failed to start shell on %s: %v", host, err)
+		}
+	} else {
+		if err := session.Start(command); err != nil {
+			return fmt.Errorf("Failed to start `%s` on %s: %v", command, host, err)
+		}
+	}
+	return session.Wait()
+}
+
+// forwardWindowResize watches the local terminal for SIGWINCH and forwards
+// the new size to the remote PTY via session.WindowChange. The returned
+// channel should be closed once the session ends to stop the goroutine.
+func forwardWindowResize(session *ssh.Session, fd int) chan struct{} {
+	stop := make(chan struct{})
+	resize := make(chan os.Signal, 1)
+	signal.Notify(resize, syscall.SIGWINCH)
+
+	go func() {
+		defer signal.Stop(resize)
+		for {
+			select {
+			case <-resize:
+				if w, h, err := terminal.GetSize(fd); err == nil {
+					session.WindowChange(h, w)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return stop
+}
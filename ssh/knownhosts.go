@@ -0,0 +1,69 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/fabric8io/gosupervise/log"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// acceptNewHostKeyCallback verifies a server's host key against file,
+// appending it to file and accepting the connection the first time a host
+// is seen, but rejecting any later connection whose key no longer matches
+// what was recorded - i.e. OpenSSH's `StrictHostKeyChecking=accept-new`.
+func acceptNewHostKeyCallback(file string) (ssh.HostKeyCallback, error) {
+	if err := ensureFileExists(file); err != nil {
+		return nil, err
+	}
+	verify, err := knownhosts.New(file)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load known_hosts file %s: %v", file, err)
+	}
+
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		err := verify(hostname, remote, key)
+		if err == nil {
+			return nil
+		}
+		var keyErr *knownhosts.KeyError
+		if !errors.As(err, &keyErr) || len(keyErr.Want) > 0 {
+			// Either an unexpected error, or the host is known but
+			// presented a different key - a possible MITM, so fail.
+			return err
+		}
+		return appendKnownHost(file, hostname, remote, key)
+	}, nil
+}
+
+// ensureFileExists creates file if it doesn't already exist, so that
+// knownhosts.New can load a brand new known_hosts file on a host's very
+// first connection instead of failing outright.
+func ensureFileExists(file string) error {
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("Failed to create known_hosts file %s: %v", file, err)
+	}
+	return f.Close()
+}
+
+// appendKnownHost records a newly seen host key in file so that subsequent
+// connections are verified against it.
+func appendKnownHost(file string, hostname string, remote net.Addr, key ssh.PublicKey) error {
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("Failed to open known_hosts file %s: %v", file, err)
+	}
+	defer f.Close()
+
+	line := knownhosts.Line([]string{knownhosts.Normalize(hostname)}, key)
+	if _, err := fmt.Fprintln(f, line); err != nil {
+		return fmt.Errorf("Failed to append to known_hosts file %s: %v", file, err)
+	}
+	log.Info("Added new host key for %s to %s", hostname, file)
+	return nil
+}
@@ -0,0 +1,189 @@
+package ssh
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/fabric8io/gosupervise/log"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// PortMapping is a single local:remote (or, for reverse tunnels,
+// remote:local) port pair to forward.
+type PortMapping struct {
+	LocalPort  string
+	RemotePort string
+}
+
+// ParsePortMapping parses a "localPort:remotePort" command line argument
+// into a PortMapping.
+func ParsePortMapping(spec string) (*PortMapping, error) {
+	parts := strings.SplitN(spec, ":", 2)
+	if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+		return nil, fmt.Errorf("Invalid port mapping %s, expected localPort:remotePort", spec)
+	}
+	return &PortMapping{LocalPort: parts[0], RemotePort: parts[1]}, nil
+}
+
+// PortForward connects to host:port as user and forwards each of the given
+// port mappings, analogous to `ssh -L`/`ssh -R`. When reverse is false
+// connections to localPort are tunnelled to remotePort on the remote host
+// (`-L` semantics); when reverse is true connections to remotePort on the
+// remote host are tunnelled back to localPort on this machine (`-R`
+// semantics). It blocks until the SSH connection is closed, and tears down
+// every other mapping as soon as any one of them fails, returning that
+// mapping's error.
+func PortForward(user string, auth AuthConfig, host string, port string, mappings []PortMapping, reverse bool) error {
+	client, err := dial(user, auth, host, port)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	listeners := newCloserSet()
+	go func() {
+		// Stop accepting on every mapping once the SSH connection closes.
+		client.Wait()
+		listeners.closeAll()
+	}()
+	errs := make(chan error, len(mappings))
+	var wg sync.WaitGroup
+	for _, mapping := range mappings {
+		wg.Add(1)
+		go func(mapping PortMapping) {
+			defer wg.Done()
+			var err error
+			if reverse {
+				err = forwardRemoteToLocal(client, mapping, listeners)
+			} else {
+				err = forwardLocalToRemote(client, mapping, listeners)
+			}
+			if err != nil {
+				errs <- err
+			}
+		}(mapping)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case err := <-errs:
+		listeners.closeAll()
+		client.Close()
+		<-done
+		return err
+	case <-done:
+		select {
+		case err := <-errs:
+			return err
+		default:
+			return nil
+		}
+	}
+}
+
+// closerSet tracks the listeners currently forwarding traffic so that
+// PortForward can tear all of them down as soon as one mapping fails.
+type closerSet struct {
+	mu      sync.Mutex
+	closers []io.Closer
+}
+
+func newCloserSet() *closerSet {
+	return &closerSet{}
+}
+
+func (s *closerSet) add(c io.Closer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closers = append(s.closers, c)
+}
+
+func (s *closerSet) closeAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range s.closers {
+		c.Close()
+	}
+}
+
+// forwardLocalToRemote listens on mapping.LocalPort and tunnels each
+// accepted connection to mapping.RemotePort on the remote host.
+func forwardLocalToRemote(client *ssh.Client, mapping PortMapping, listeners *closerSet) error {
+	localAddr := fmt.Sprintf("localhost:%s", mapping.LocalPort)
+	listener, err := net.Listen("tcp", localAddr)
+	if err != nil {
+		return fmt.Errorf("Failed to listen on %s: %v", localAddr, err)
+	}
+	defer listener.Close()
+	listeners.add(listener)
+
+	log.Info("Forwarding local port %s to remote port %s", mapping.LocalPort, mapping.RemotePort)
+	for {
+		localConn, err := listener.Accept()
+		if err != nil {
+			return fmt.Errorf("Failed to accept connection on %s: %v", localAddr, err)
+		}
+		remoteConn, err := client.Dial("tcp", fmt.Sprintf("localhost:%s", mapping.RemotePort))
+		if err != nil {
+			log.Err("Failed to dial remote port %s: %v", mapping.RemotePort, err)
+			localConn.Close()
+			continue
+		}
+		go proxy(localConn, remoteConn)
+	}
+}
+
+// forwardRemoteToLocal asks the remote host to listen on mapping.RemotePort
+// and tunnels each accepted connection to mapping.LocalPort on this
+// machine.
+func forwardRemoteToLocal(client *ssh.Client, mapping PortMapping, listeners *closerSet) error {
+	remoteAddr := fmt.Sprintf("localhost:%s", mapping.RemotePort)
+	listener, err := client.Listen("tcp", remoteAddr)
+	if err != nil {
+		return fmt.Errorf("Failed to listen on remote %s: %v", remoteAddr, err)
+	}
+	defer listener.Close()
+	listeners.add(listener)
+
+	log.Info("Forwarding remote port %s to local port %s", mapping.RemotePort, mapping.LocalPort)
+	for {
+		remoteConn, err := listener.Accept()
+		if err != nil {
+			return fmt.Errorf("Failed to accept connection on remote %s: %v", remoteAddr, err)
+		}
+		localConn, err := net.Dial("tcp", fmt.Sprintf("localhost:%s", mapping.LocalPort))
+		if err != nil {
+			log.Err("Failed to dial local port %s: %v", mapping.LocalPort, err)
+			remoteConn.Close()
+			continue
+		}
+		go proxy(remoteConn, localConn)
+	}
+}
+
+// proxy copies data in both directions between two connections until
+// either side closes.
+func proxy(a, b io.ReadWriteCloser) {
+	defer a.Close()
+	defer b.Close()
+
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(a, b)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(b, a)
+		done <- struct{}{}
+	}()
+	<-done
+}
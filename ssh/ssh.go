@@ -0,0 +1,56 @@
+// Package ssh runs commands on remote hosts over SSH on behalf of the
+// supervisor pod.
+package ssh
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fabric8io/gosupervise/log"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// RemoteSshCommand connects to host:port as user, authenticating and
+// verifying the server as described by auth, and runs command, streaming
+// its stdout/stderr to this process. When tty is true the command is run in
+// an interactive PTY session instead, so that the caller lands in a live
+// shell on the remote host (see RunInteractive).
+func RemoteSshCommand(user string, auth AuthConfig, host string, port string, command string, tty bool) error {
+	client, err := dial(user, auth, host, port)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if tty {
+		return RunInteractive(client, host, command)
+	}
+
+	session, err := client.NewSession()
+	if err != nil {
+		return fmt.Errorf("Failed to create SSH session to %s: %v", host, err)
+	}
+	defer session.Close()
+
+	session.Stdout = os.Stdout
+	session.Stderr = os.Stderr
+	session.Stdin = os.Stdin
+
+	log.Info("Running `%s` on %s@%s:%s", command, user, host, port)
+	return session.Run(command)
+}
+
+func dial(user string, auth AuthConfig, host string, port string) (*ssh.Client, error) {
+	config, err := auth.clientConfig(user)
+	if err != nil {
+		return nil, err
+	}
+
+	addr := fmt.Sprintf("%s:%s", host, port)
+	client, err := ssh.Dial("tcp", addr, config)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to dial %s: %v", addr, err)
+	}
+	return client, nil
+}